@@ -0,0 +1,121 @@
+// Package auth implements the IIIF Authentication API 1.0: a Policy
+// interface that IIIFHandler.Route consults before serving info.json or
+// image commands, plus backends institutions can plug in (JWT bearer
+// tokens, IP allowlists, HMAC-signed URLs, and a Go plugin hook for
+// Shibboleth/OAuth).
+package auth
+
+import (
+	"net/http"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+// Decision is the outcome of evaluating a Policy against a request.
+type Decision int
+
+const (
+	// Allow means the request may proceed unmodified.
+	Allow Decision = iota
+
+	// Deny401 means the request should be rejected with 401 and a
+	// WWW-Authenticate challenge.
+	Deny401
+
+	// Deny403 means the request should be rejected outright with 403; no
+	// amount of re-authentication will help.
+	Deny403
+
+	// Degrade means the request should be served a substitute response
+	// (e.g. a lower-resolution identifier) rather than denied outright.
+	Degrade
+)
+
+// Result is what a Policy returns after evaluating a request.
+type Result struct {
+	Decision Decision
+
+	// Realm is sent in the WWW-Authenticate header on Deny401.
+	Realm string
+
+	// DegradedID is the identifier to serve instead, when Decision is
+	// Degrade.
+	DegradedID iiif.ID
+
+	// Services, when non-nil, is embedded in info.json's auth services
+	// block ("login", "token", "logout") per the IIIF Auth spec.
+	Services *Services
+}
+
+// Services describes the login/token/logout endpoints a client should use
+// to authenticate, per the IIIF Authentication API's info.json services
+// block.
+type Services struct {
+	Login  string `json:"login,omitempty"`
+	Token  string `json:"token,omitempty"`
+	Logout string `json:"logout,omitempty"`
+}
+
+// IIIF Authentication API 1.0's fixed context and service profile URIs.
+const (
+	authContext   = "http://iiif.io/api/auth/1/context.json"
+	loginProfile  = "http://iiif.io/api/auth/1/login"
+	tokenProfile  = "http://iiif.io/api/auth/1/token"
+	logoutProfile = "http://iiif.io/api/auth/1/logout"
+)
+
+// Describe renders s as a spec-compliant IIIF Authentication API service
+// object (with "@context", "@id", "profile", and a nested "service" array
+// for token/logout), suitable for embedding under info.json's top-level
+// "service" key.
+func (s *Services) Describe() map[string]interface{} {
+	login := map[string]interface{}{
+		"@context": authContext,
+		"@id":      s.Login,
+		"profile":  loginProfile,
+		"label":    "Login",
+	}
+
+	var sub []map[string]interface{}
+	if s.Token != "" {
+		sub = append(sub, map[string]interface{}{"@id": s.Token, "profile": tokenProfile})
+	}
+	if s.Logout != "" {
+		sub = append(sub, map[string]interface{}{"@id": s.Logout, "profile": logoutProfile, "label": "Logout"})
+	}
+	if len(sub) > 0 {
+		login["service"] = sub
+	}
+
+	return login
+}
+
+// Policy decides whether a request for a given identifier should be
+// allowed, denied, or served a degraded substitute.
+type Policy interface {
+	// Evaluate inspects req (and the identifier it's requesting) and
+	// returns a Result describing how IIIFHandler should proceed.
+	Evaluate(req *http.Request, id iiif.ID) Result
+}
+
+// Apply writes the appropriate status/headers for a non-Allow Result and
+// reports whether the caller should stop handling the request.
+func Apply(w http.ResponseWriter, result Result) (handled bool) {
+	switch result.Decision {
+	case Allow:
+		return false
+	case Deny401:
+		realm := result.Realm
+		if realm == "" {
+			realm = "RAIS"
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return true
+	case Deny403:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	default:
+		return false
+	}
+}
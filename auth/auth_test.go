@@ -0,0 +1,38 @@
+package auth
+
+import "testing"
+
+func TestServicesDescribeShape(t *testing.T) {
+	s := &Services{Login: "https://example.org/login", Token: "https://example.org/token", Logout: "https://example.org/logout"}
+	desc := s.Describe()
+
+	if desc["@context"] != authContext {
+		t.Fatalf("@context = %v, want %v", desc["@context"], authContext)
+	}
+	if desc["@id"] != s.Login {
+		t.Fatalf("@id = %v, want %v", desc["@id"], s.Login)
+	}
+	if desc["profile"] != loginProfile {
+		t.Fatalf("profile = %v, want %v", desc["profile"], loginProfile)
+	}
+
+	sub, ok := desc["service"].([]map[string]interface{})
+	if !ok || len(sub) != 2 {
+		t.Fatalf("service = %#v, want a 2-entry token/logout array", desc["service"])
+	}
+	if sub[0]["@id"] != s.Token || sub[0]["profile"] != tokenProfile {
+		t.Fatalf("token sub-service = %#v", sub[0])
+	}
+	if sub[1]["@id"] != s.Logout || sub[1]["profile"] != logoutProfile {
+		t.Fatalf("logout sub-service = %#v", sub[1])
+	}
+}
+
+func TestServicesDescribeLoginOnly(t *testing.T) {
+	s := &Services{Login: "https://example.org/login"}
+	desc := s.Describe()
+
+	if _, ok := desc["service"]; ok {
+		t.Fatalf("service sub-array should be omitted when there's no token/logout endpoint, got %#v", desc["service"])
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+func signedRequest(p *HMACPolicy, path string, ttl time.Duration) *http.Request {
+	expires, sig := p.Sign(path, ttl)
+	req := httptest.NewRequest("GET", path+"?expires="+expires+"&sig="+sig, nil)
+	return req
+}
+
+func TestHMACPolicyAllowsValidSignature(t *testing.T) {
+	p := NewHMACPolicy([]byte("secret"))
+	req := signedRequest(p, "/iiif/2/foo/full/full/0/default.jpg", time.Minute)
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Allow {
+		t.Fatalf("Evaluate() = %v, want Allow", got.Decision)
+	}
+}
+
+func TestHMACPolicyDeniesExpired(t *testing.T) {
+	p := NewHMACPolicy([]byte("secret"))
+	req := signedRequest(p, "/iiif/2/foo/full/full/0/default.jpg", -time.Minute)
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() on an expired signature = %v, want Deny403", got.Decision)
+	}
+}
+
+func TestHMACPolicyDeniesTamperedPath(t *testing.T) {
+	p := NewHMACPolicy([]byte("secret"))
+	expires, sig := p.Sign("/iiif/2/foo/full/full/0/default.jpg", time.Minute)
+	req := httptest.NewRequest("GET", "/iiif/2/bar/full/full/0/default.jpg?expires="+expires+"&sig="+sig, nil)
+
+	got := p.Evaluate(req, iiif.ID("bar"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() with a signature for a different path = %v, want Deny403", got.Decision)
+	}
+}
+
+func TestHMACPolicyDeniesWrongSecret(t *testing.T) {
+	signer := NewHMACPolicy([]byte("secret"))
+	verifier := NewHMACPolicy([]byte("different"))
+	req := signedRequest(signer, "/iiif/2/foo/full/full/0/default.jpg", time.Minute)
+
+	got := verifier.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() against the wrong secret = %v, want Deny403", got.Decision)
+	}
+}
+
+func TestHMACPolicyDeniesMissingParams(t *testing.T) {
+	p := NewHMACPolicy([]byte("secret"))
+	req := httptest.NewRequest("GET", "/iiif/2/foo/full/full/0/default.jpg", nil)
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() with no expires/sig = %v, want Deny403", got.Decision)
+	}
+}
+
+func TestHMACPolicyDeniesGarbageExpires(t *testing.T) {
+	p := NewHMACPolicy([]byte("secret"))
+	req := httptest.NewRequest("GET", "/iiif/2/foo/full/full/0/default.jpg?expires=not-a-number&sig="+strconv.Itoa(0), nil)
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() with an unparseable expires = %v, want Deny403", got.Decision)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+func TestIPAllowPolicyAllowsMatchingRemoteAddr(t *testing.T) {
+	p, err := NewIPAllowPolicy([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Allow {
+		t.Fatalf("Evaluate() = %v, want Allow", got.Decision)
+	}
+}
+
+func TestIPAllowPolicyDeniesOutsideRange(t *testing.T) {
+	p, err := NewIPAllowPolicy([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "192.168.1.1:5555"
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() = %v, want Deny403", got.Decision)
+	}
+}
+
+// TestIPAllowPolicyHonorsXForwardedForFromTrustedProxy checks that a
+// request proxied through a configured trusted proxy (RemoteAddr is the
+// proxy, not the client) is evaluated against the real client IP in
+// X-Forwarded-For. Without this, every request behind a load balancer
+// would be denied.
+func TestIPAllowPolicyHonorsXForwardedForFromTrustedProxy(t *testing.T) {
+	p, err := NewIPAllowPolicyBehindProxy([]string{"10.0.0.0/8"}, []string{"192.168.1.0/24"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicyBehindProxy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "192.168.1.1:5555" // the trusted proxy
+	req.Header.Set("X-Forwarded-For", "10.2.3.4, 192.168.1.1")
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Allow {
+		t.Fatalf("Evaluate() with X-Forwarded-For naming an allowed client = %v, want Allow", got.Decision)
+	}
+}
+
+// TestIPAllowPolicyIgnoresXForwardedForFromUntrustedSource checks that a
+// client connecting directly (not through a configured trusted proxy)
+// can't bypass the allowlist just by setting X-Forwarded-For itself.
+func TestIPAllowPolicyIgnoresXForwardedForFromUntrustedSource(t *testing.T) {
+	p, err := NewIPAllowPolicyBehindProxy([]string{"10.0.0.0/8"}, []string{"192.168.1.0/24"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicyBehindProxy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "203.0.113.5:5555" // not a trusted proxy
+	req.Header.Set("X-Forwarded-For", "10.2.3.4")
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() with a forged X-Forwarded-For from an untrusted source = %v, want Deny403", got.Decision)
+	}
+}
+
+// TestIPAllowPolicyWithoutTrustedProxiesIgnoresXForwardedFor checks that
+// the plain NewIPAllowPolicy constructor (no trusted proxies configured)
+// never honors X-Forwarded-For at all, which is the safe default for a
+// directly-reachable RAIS instance.
+func TestIPAllowPolicyWithoutTrustedProxiesIgnoresXForwardedFor(t *testing.T) {
+	p, err := NewIPAllowPolicy([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "192.168.1.1:5555"
+	req.Header.Set("X-Forwarded-For", "10.2.3.4")
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() = %v, want Deny403 (RemoteAddr itself is outside the allowlist)", got.Decision)
+	}
+}
+
+func TestIPAllowPolicyDeniesMalformedAddr(t *testing.T) {
+	p, err := NewIPAllowPolicy([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewIPAllowPolicy: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	req.RemoteAddr = "not-an-address"
+
+	got := p.Evaluate(req, iiif.ID("foo"))
+	if got.Decision != Deny403 {
+		t.Fatalf("Evaluate() with an unparseable RemoteAddr = %v, want Deny403", got.Decision)
+	}
+}
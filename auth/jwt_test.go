@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest("GET", "/iiif/2/foo/info.json", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestJWTPolicyAllowsValidHS256(t *testing.T) {
+	secret := []byte("secret")
+	p := NewHS256Policy("realm", secret)
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "tester"})
+	signed, err := tok.SignedString(secret)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	got := p.Evaluate(bearerRequest(signed), iiif.ID("foo"))
+	if got.Decision != Allow {
+		t.Fatalf("Evaluate() = %v, want Allow", got.Decision)
+	}
+}
+
+func TestJWTPolicyDeniesWrongSecret(t *testing.T) {
+	p := NewHS256Policy("realm", []byte("secret"))
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "tester"})
+	signed, err := tok.SignedString([]byte("wrong"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	got := p.Evaluate(bearerRequest(signed), iiif.ID("foo"))
+	if got.Decision != Deny401 {
+		t.Fatalf("Evaluate() with the wrong secret = %v, want Deny401", got.Decision)
+	}
+}
+
+// TestJWTPolicyRejectsAlgConfusion checks that an HS256-configured policy
+// won't accept a token that claims to be signed with "none", the classic
+// algorithm-confusion bypass for naive JWT verifiers.
+func TestJWTPolicyRejectsAlgConfusion(t *testing.T) {
+	p := NewHS256Policy("realm", []byte("secret"))
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "tester"})
+	signed, err := tok.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	got := p.Evaluate(bearerRequest(signed), iiif.ID("foo"))
+	if got.Decision != Deny401 {
+		t.Fatalf("Evaluate() of an alg=none token against an HS256 policy = %v, want Deny401", got.Decision)
+	}
+}
+
+func TestJWTPolicyDeniesMissingHeader(t *testing.T) {
+	p := NewHS256Policy("realm", []byte("secret"))
+	got := p.Evaluate(bearerRequest(""), iiif.ID("foo"))
+	if got.Decision != Deny401 {
+		t.Fatalf("Evaluate() with no Authorization header = %v, want Deny401", got.Decision)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package auth
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin opens a Go plugin (.so) built against this package and
+// returns the Policy it exports, letting institutions bolt on
+// Shibboleth/OAuth or other bespoke auth schemes without RAIS needing to
+// know about them at compile time. The plugin must export a package-level
+// variable named "Policy" implementing the Policy interface.
+func LoadPlugin(path string) (Policy, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: opening plugin %q: %w", path, err)
+	}
+
+	sym, err := p.Lookup("Policy")
+	if err != nil {
+		return nil, fmt.Errorf("auth: plugin %q does not export a Policy symbol: %w", path, err)
+	}
+
+	policy, ok := sym.(Policy)
+	if !ok {
+		return nil, fmt.Errorf("auth: plugin %q's Policy symbol does not implement auth.Policy", path)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,118 @@
+// Package cache provides a two-tier cache for IIIF info.json and tile
+// responses: an in-process, byte-capped LRU for hot entries, backed by an
+// optional on-disk write-through store for entries that fall out of
+// memory. Both tiers are keyed by the same Key, letting IIIFHandler.Info
+// and IIIFHandler.Command consult the cache before invoking the decoder.
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Key identifies a single cached response.
+type Key struct {
+	Identifier string
+	URL        string // IIIF request URL as given, e.g. "/iiif/foo/full/full/0/default.jpg"; not canonicalized, so differently spelled equivalent URLs get separate entries
+	Format     string
+	Quality    int
+}
+
+// String renders Key as a stable cache key string.
+func (k Key) String() string {
+	return fmt.Sprintf("%s|%s|%s|%d", k.Identifier, k.URL, k.Format, k.Quality)
+}
+
+// Entry is a single cached response body plus the metadata needed to
+// answer conditional requests (ETag, Last-Modified) without re-deriving
+// it from the source file on every hit.
+type Entry struct {
+	Body        []byte
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+// Size is the number of bytes Entry counts against the LRU's byte budget.
+func (e *Entry) Size() int64 {
+	return int64(len(e.Body))
+}
+
+// Cache is a two-tier store: a hot in-process LRU, optionally backed by
+// an on-disk write-through store for entries evicted from memory.
+type Cache struct {
+	mu    sync.Mutex
+	lru   *lru
+	disk  *diskStore
+	hits  uint64
+	total uint64
+}
+
+// New returns a Cache with an in-process LRU capped at maxBytes. If dir
+// is non-empty, evicted (and newly-stored) entries are also written
+// through to disk under dir, and a miss falls back to reading from there
+// before hitting the decoder.
+func New(maxBytes int64, dir string) *Cache {
+	c := &Cache{lru: newLRU(maxBytes)}
+	if dir != "" {
+		c.disk = newDiskStore(dir)
+	}
+	return c
+}
+
+// Get returns the cached Entry for key, if any.
+func (c *Cache) Get(key Key) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total++
+
+	if e, ok := c.lru.get(key.String()); ok {
+		c.hits++
+		Hits.Inc()
+		return e, true
+	}
+
+	if c.disk != nil {
+		if e, ok := c.disk.get(key.String()); ok {
+			c.lru.set(key.String(), e)
+			c.hits++
+			Hits.Inc()
+			return e, true
+		}
+	}
+
+	Misses.Inc()
+	return nil, false
+}
+
+// Set stores e under key in the in-process LRU, and write-through to disk
+// if a disk tier is configured.
+func (c *Cache) Set(key Key, e *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.set(key.String(), e)
+	if c.disk != nil {
+		c.disk.set(key.String(), e)
+	}
+}
+
+// Flush empties both tiers, for the admin flush endpoint.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.clear()
+	if c.disk != nil {
+		c.disk.clear()
+	}
+}
+
+// Stats returns (hits, total) lookups served since the cache was created.
+// Flush empties the tiers but does not reset these counters.
+func (c *Cache) Stats() (hits, total uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.total
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func entry(body string) *Entry {
+	return &Entry{Body: []byte(body), ContentType: "application/json", ETag: body, ModTime: time.Now()}
+}
+
+func TestCacheGetSetRoundTrip(t *testing.T) {
+	c := New(1<<20, "")
+	key := Key{Identifier: "foo", URL: "info.json", Format: "application/json"}
+
+	if _, ok := c.Get(key); ok {
+		t.Fatalf("Get on an empty cache should miss")
+	}
+
+	c.Set(key, entry("hello"))
+	got, ok := c.Get(key)
+	if !ok || string(got.Body) != "hello" {
+		t.Fatalf("Get() = %v, %v, want \"hello\", true", got, ok)
+	}
+}
+
+// TestLRUEvictsLeastRecentlyUsed checks that once the byte budget is
+// exceeded, the entry that hasn't been touched (neither Set nor a hit via
+// Get) the longest is the one dropped, not simply the first one inserted.
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	l := newLRU(10)
+
+	l.set("a", entry("12345")) // 5 bytes
+	l.set("b", entry("12345")) // 5 bytes, now at budget (10)
+
+	// Touch "a" so "b" becomes the least-recently-used entry.
+	l.get("a")
+
+	l.set("c", entry("12345")) // pushes over budget; "b" should be evicted, not "a"
+
+	if _, ok := l.get("b"); ok {
+		t.Fatalf("\"b\" should have been evicted as least-recently-used")
+	}
+	if _, ok := l.get("a"); !ok {
+		t.Fatalf("\"a\" was touched more recently than \"b\" and should have survived eviction")
+	}
+	if _, ok := l.get("c"); !ok {
+		t.Fatalf("\"c\" was just inserted and should be present")
+	}
+}
+
+func TestCacheDiskFallback(t *testing.T) {
+	dir := t.TempDir()
+	key := Key{Identifier: "foo", URL: "info.json", Format: "application/json"}
+
+	c := New(1<<20, dir)
+	c.Set(key, entry("hello"))
+
+	// A fresh Cache sharing the same disk dir has an empty in-process LRU,
+	// so this Get can only succeed via the disk tier.
+	c2 := New(1<<20, dir)
+	got, ok := c2.Get(key)
+	if !ok || string(got.Body) != "hello" {
+		t.Fatalf("Get() via disk fallback = %v, %v, want \"hello\", true", got, ok)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := New(1<<20, "")
+	key := Key{Identifier: "foo", URL: "info.json", Format: "application/json"}
+
+	c.Get(key) // miss
+	c.Set(key, entry("hello"))
+	c.Get(key) // hit
+
+	hits, total := c.Stats()
+	if hits != 1 || total != 2 {
+		t.Fatalf("Stats() = %d, %d, want 1, 2", hits, total)
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// diskStore is the on-disk write-through tier: each entry is gob-encoded
+// into its own file, named by the SHA-256 of its cache key so arbitrary
+// identifiers/URLs don't have to be filesystem-safe.
+type diskStore struct {
+	dir string
+}
+
+func newDiskStore(dir string) *diskStore {
+	os.MkdirAll(dir, 0755)
+	return &diskStore{dir: dir}
+}
+
+func (d *diskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+func (d *diskStore) get(key string) (*Entry, bool) {
+	f, err := os.Open(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var e Entry
+	if err := gob.NewDecoder(f).Decode(&e); err != nil {
+		return nil, false
+	}
+	return &e, true
+}
+
+func (d *diskStore) set(key string, e *Entry) {
+	f, err := os.Create(d.path(key))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	gob.NewEncoder(f).Encode(e)
+}
+
+func (d *diskStore) clear() {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		os.Remove(filepath.Join(d.dir, entry.Name()))
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import "container/list"
+
+// lru is a byte-capped, least-recently-used cache of Entry values. It is
+// not safe for concurrent use on its own; Cache guards it with a mutex.
+type lru struct {
+	maxBytes int64
+	curBytes int64
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+func newLRU(maxBytes int64) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (l *lru) get(key string) (*Entry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+func (l *lru) set(key string, e *Entry) {
+	if el, ok := l.items[key]; ok {
+		l.curBytes -= el.Value.(*lruItem).entry.Size()
+		el.Value = &lruItem{key: key, entry: e}
+		l.order.MoveToFront(el)
+		l.curBytes += e.Size()
+	} else {
+		el := l.order.PushFront(&lruItem{key: key, entry: e})
+		l.items[key] = el
+		l.curBytes += e.Size()
+	}
+
+	for l.curBytes > l.maxBytes && l.order.Len() > 0 {
+		l.evictOldest()
+	}
+}
+
+func (l *lru) evictOldest() {
+	el := l.order.Back()
+	if el == nil {
+		return
+	}
+	item := el.Value.(*lruItem)
+	l.curBytes -= item.entry.Size()
+	l.order.Remove(el)
+	delete(l.items, item.key)
+}
+
+func (l *lru) clear() {
+	l.items = make(map[string]*list.Element)
+	l.order = list.New()
+	l.curBytes = 0
+}
@@ -0,0 +1,22 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Hits and Misses count cache lookups so operators can see how
+// effectively the two-tier cache is absorbing repeat info.json and tile
+// requests.
+var (
+	Hits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rais_cache_hits_total",
+		Help: "Count of cache lookups satisfied by either the in-process LRU or the on-disk tier.",
+	})
+
+	Misses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rais_cache_misses_total",
+		Help: "Count of cache lookups that required invoking the decoder.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(Hits, Misses)
+}
@@ -1,17 +1,183 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/uoregon-libraries/rais-image-server/auth"
+	"github.com/uoregon-libraries/rais-image-server/cache"
+	"github.com/uoregon-libraries/rais-image-server/encoder"
 	"github.com/uoregon-libraries/rais-image-server/iiif"
-	"image/jpeg"
+	"github.com/uoregon-libraries/rais-image-server/orientation"
+	"github.com/uoregon-libraries/rais-image-server/resolver"
+	"github.com/uoregon-libraries/rais-image-server/rotate"
+	"github.com/uoregon-libraries/rais-image-server/scheduler"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// AuthPolicy, when non-nil, is consulted by Route before serving
+// info.json or image commands. A nil AuthPolicy means every request is
+// allowed, preserving RAIS's original unauthenticated behavior.
+var AuthPolicy auth.Policy
+
+// respCache holds cached info.json and tile responses.  It's configured
+// once via InitCache during server startup; a nil respCache means
+// caching is off, and Info/Command fall straight through to the decoder.
+var respCache *cache.Cache
+
+// InitCache sets up the package-level response cache.  maxBytes bounds
+// the in-process LRU; if dir is non-empty, evicted entries are also
+// write-through to disk under dir.
+func InitCache(maxBytes int64, dir string) {
+	respCache = cache.New(maxBytes, dir)
+}
+
+// FlushCache empties the response cache. It's meant to be wired to an
+// admin-only endpoint by the server's main().
+func FlushCache(w http.ResponseWriter, req *http.Request) {
+	if respCache != nil {
+		respCache.Flush()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableOrientation turns off EXIF orientation correction, for
+// operators who'd rather trade correctness for the extra file read and
+// parse this costs on every info.json request.
+var DisableOrientation bool
+
+// orientationMaxRead caps how many leading bytes of a source file we read
+// to look for an EXIF orientation tag or embedded ICC profile; both live
+// in the header, so we never need the whole file.
+const orientationMaxRead = 64 * 1024
+
+// maxRequestedQuality caps the ?q= query parameter so a request can't ask
+// for more than the server is willing to spend encoding.
+const maxRequestedQuality = 95
+
+// retryAfterSeconds is the value sent in the Retry-After header when the
+// scaler pool sheds a request for being too busy.
+const retryAfterSeconds = 5
+
+// scaler serializes expensive decode/resize work behind a bounded worker
+// pool so one client can't exhaust CPU.  It's configured once via
+// InitScaler during server startup.
+var scaler *scheduler.Scheduler
+
+// InitScaler sets up the package-level resize scheduler.  maxProcs bounds
+// how many resizes may run concurrently; maxQueue bounds how many more
+// may wait for a slot; waitTimeout bounds how long a request waits before
+// getting a 503.
+func InitScaler(maxProcs, maxQueue int, waitTimeout time.Duration) {
+	scaler = scheduler.New(maxProcs, maxQueue, waitTimeout)
+}
+
+// hasEncoder reports whether an Encoder is registered for the given IIIF
+// format suffix, so FeatureSet only advertises formats this binary was
+// actually built with (e.g. Webp stays false unless built with -tags webp).
+func hasEncoder(format string) bool {
+	_, ok := encoder.Lookup(format)
+	return ok
+}
+
+// rotateBackground returns the color rotate.Rotate should pad a rotated
+// image's newly-exposed corners with: transparent for formats with an
+// alpha channel, opaque white for formats that would otherwise flatten
+// transparency to black.
+func rotateBackground(format string) color.Color {
+	switch format {
+	case "png", "webp", "gif", "avif":
+		return color.Transparent
+	default:
+		return color.White
+	}
+}
+
+// negotiateFormat picks the output format for a command request.  The
+// IIIF URL suffix is authoritative when it names a format we have an
+// encoder for; otherwise we fall back to the client's Accept header,
+// preferring whichever supported format appears first in it.
+func negotiateFormat(req *http.Request, u *iiif.URL) string {
+	if _, ok := encoder.Lookup(string(u.Format)); ok {
+		return string(u.Format)
+	}
+
+	for _, h := range req.Header["Accept"] {
+		for _, accept := range strings.Split(h, ",") {
+			accept = strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+			for _, format := range encoder.SupportedFormats() {
+				if ct, ok := encoder.Lookup(format); ok && ct.ContentType() == accept {
+					return format
+				}
+			}
+		}
+	}
+
+	return "jpg"
+}
+
+// requestedQuality reads the ?q= query parameter, clamping it to
+// maxRequestedQuality.  A missing or invalid value yields 0, which tells
+// the Encoder to use its own default.
+func requestedQuality(req *http.Request) int {
+	q, err := strconv.Atoi(req.URL.Query().Get("q"))
+	if err != nil || q <= 0 {
+		return 0
+	}
+	if q > maxRequestedQuality {
+		return maxRequestedQuality
+	}
+	return q
+}
+
+// sourceETag derives a strong ETag from the source file's mtime and size,
+// so cached entries invalidate automatically when the underlying file
+// changes without us having to checksum its contents.
+func sourceETag(path string) (etag string, modTime time.Time, ok bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return fmt.Sprintf(`"%x-%x"`, fi.ModTime().Unix(), fi.Size()), fi.ModTime(), true
+}
+
+// notModified checks the request's If-None-Match/If-Modified-Since
+// headers against e, writing a 304 and returning true if the client's
+// cached copy is still current.
+func notModified(w http.ResponseWriter, req *http.Request, e *cache.Entry) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" && inm == e.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !e.ModTime.After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheHeaders sets the ETag and Cache-Control response headers for a
+// cacheable entry.
+func setCacheHeaders(w http.ResponseWriter, e *cache.Entry) {
+	w.Header().Set("ETag", e.ETag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+}
+
 func acceptsLD(req *http.Request) bool {
 	for _, h := range req.Header["Accept"] {
 		for _, accept := range strings.Split(h, ",") {
@@ -31,18 +197,43 @@ type IIIFHandler struct {
 	FeatureSet    *iiif.FeatureSet
 	InfoPathRegex *regexp.Regexp
 	TilePath      string
+	Resolver      resolver.Resolver
 }
 
 func NewIIIFHandler(u *url.URL, widths []int, tp string) *IIIFHandler {
+	res, err := resolver.New(tp)
+	if err != nil {
+		log.Fatalf("Unable to set up identifier resolver for %q: %s", tp, err)
+	}
 	// The base feature set is level 1, then we add our extra features, tile sizes, etc
 	fs := iiif.FeatureSet1()
 	fs.RotationBy90s = true
+	// Arbitrary rotation is now handled by the rotate package's software
+	// compositing stage in ImageResource.Apply, so we can advertise full
+	// rotation support rather than just multiples of 90.
+	fs.RotationArbitrary = true
+
+	// Advertise only the formats this binary was actually built with,
+	// rather than trusting FeatureSet1's defaults, which know nothing about
+	// the encoder registry.
+	fs.Jpg = hasEncoder("jpg")
+	fs.Png = hasEncoder("png")
+	fs.Gif = hasEncoder("gif")
+	fs.Tif = hasEncoder("tif")
+	fs.Webp = hasEncoder("webp")
+
 	fs.TileSizes = make([]iiif.TileSize, 0)
 	sf := []int{1, 2, 4, 8, 16, 32, 64}
 	for _, val := range widths {
 		fs.TileSizes = append(fs.TileSizes, iiif.TileSize{Width: val, ScaleFactors: sf})
 	}
 
+	// Fall back to a sane default if the server main() hasn't called
+	// InitScaler yet (e.g. in tests that construct a handler directly)
+	if scaler == nil {
+		InitScaler(4, 16, 30*time.Second)
+	}
+
 	rprefix := fmt.Sprintf(`^%s`, u.Path)
 	return &IIIFHandler{
 		Base:          u,
@@ -51,9 +242,29 @@ func NewIIIFHandler(u *url.URL, widths []int, tp string) *IIIFHandler {
 		InfoPathRegex: regexp.MustCompile(rprefix + `/([^/]+)/info.json$`),
 		TilePath:      tp,
 		FeatureSet:    fs,
+		Resolver:      res,
 	}
 }
 
+// resolvePath turns an identifier into a local file path that
+// NewImageResource can open. A FileResolver resolves directly to a path
+// on the mounted filesystem with no extra I/O; any other Resolver (HTTP,
+// S3, GCS, ...) gets spooled to a temp file first, since decoders such as
+// openjpeg need a real file descriptor. The returned cleanup func (nil
+// for the local case) removes that temp file once the caller is done
+// with it.
+func (ih *IIIFHandler) resolvePath(identifier iiif.ID) (path string, cleanup func(), err error) {
+	if fr, ok := ih.Resolver.(*resolver.FileResolver); ok {
+		return fr.Root + "/" + identifier.Path(), nil, nil
+	}
+
+	r, size, _, err := ih.Resolver.Resolve(identifier)
+	if err != nil {
+		return "", nil, err
+	}
+	return resolver.SpoolToTemp(r, size)
+}
+
 func (ih *IIIFHandler) Route(w http.ResponseWriter, req *http.Request) {
 	// Pull identifier from base so we know if we're even dealing with a valid
 	// file in the first place
@@ -68,7 +279,26 @@ func (ih *IIIFHandler) Route(w http.ResponseWriter, req *http.Request) {
 	}
 
 	identifier := iiif.ID(parts[1])
-	filepath := ih.TilePath + "/" + identifier.Path()
+
+	var authResult auth.Result
+	if AuthPolicy != nil {
+		authResult = AuthPolicy.Evaluate(req, identifier)
+		if auth.Apply(w, authResult) {
+			return
+		}
+		if authResult.Decision == auth.Degrade {
+			identifier = authResult.DegradedID
+		}
+	}
+
+	filepath, cleanup, err := ih.resolvePath(identifier)
+	if err != nil {
+		http.Error(w, "Image resource does not exist", 404)
+		return
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
 
 	res, err := NewImageResource(identifier, filepath)
 
@@ -92,7 +322,7 @@ func (ih *IIIFHandler) Route(w http.ResponseWriter, req *http.Request) {
 
 	// Check for info path, and dispatch if it matches
 	if ih.InfoPathRegex.MatchString(p) {
-		ih.Info(w, req, res)
+		ih.Info(w, req, res, authResult.Services)
 		return
 	}
 
@@ -106,35 +336,151 @@ func (ih *IIIFHandler) Route(w http.ResponseWriter, req *http.Request) {
 	http.Error(w, "Invalid IIIF request", 400)
 }
 
-func (ih *IIIFHandler) Info(w http.ResponseWriter, req *http.Request, res *ImageResource) {
-	rect, err := res.Image.GetDimensions()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Unable to read image dimensions for %#v", res.ID), 500)
-		return
+// addAuthServices merges the IIIF Authentication API's service block
+// (login/token/logout) into an already-marshaled info.json document,
+// without needing to know the concrete iiif.Info struct's fields. Any
+// service block the document already carries is preserved alongside ours
+// rather than overwritten.
+func addAuthServices(raw []byte, svc *auth.Services) ([]byte, error) {
+	if svc == nil {
+		return raw, nil
 	}
 
-	info := ih.FeatureSet.Info()
-	info.Width = rect.Dx()
-	info.Height = rect.Dy()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw, err
+	}
 
-	// The info id is actually the full URL to the resource, not just its ID
-	info.ID = ih.Base.String() + "/" + res.ID.String()
+	desc := svc.Describe()
+	switch existing := doc["service"].(type) {
+	case nil:
+		doc["service"] = desc
+	case []interface{}:
+		doc["service"] = append(existing, desc)
+	default:
+		doc["service"] = []interface{}{existing, desc}
+	}
 
-	json, err := json.Marshal(info)
+	return json.Marshal(doc)
+}
+
+func (ih *IIIFHandler) Info(w http.ResponseWriter, req *http.Request, res *ImageResource, services *auth.Services) {
+	ct := "application/json"
+	if acceptsLD(req) {
+		ct = "application/ld+json"
+	}
+
+	// The cache stores the base info.json body, with no auth services
+	// embedded: services are specific to the requesting principal, so
+	// baking them into the cached bytes would leak the first caller's
+	// login/token/logout URLs to everyone else who hits the same entry.
+	var cacheKey cache.Key
+	var cached *cache.Entry
+	if respCache != nil {
+		_, modTime, ok := sourceETag(res.FilePath)
+		cacheKey = cache.Key{Identifier: res.ID.String(), URL: "info.json", Format: ct}
+		if ok {
+			if e, hit := respCache.Get(cacheKey); hit && e.ModTime.Equal(modTime) {
+				cached = e
+			}
+		}
+	}
+
+	var body []byte
+	if cached != nil {
+		body = cached.Body
+	} else {
+		rect, err := res.Image.GetDimensions()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Unable to read image dimensions for %#v", res.ID), 500)
+			return
+		}
+
+		info := ih.FeatureSet.Info()
+		info.Width, info.Height = rect.Dx(), rect.Dy()
+		if !DisableOrientation {
+			if tag := readOrientation(res.FilePath); tag != orientation.Normal {
+				info.Width, info.Height = tag.Dimensions(rect.Dx(), rect.Dy())
+			}
+		}
+
+		// The info id is actually the full URL to the resource, not just its ID
+		info.ID = ih.Base.String() + "/" + res.ID.String()
+
+		var err2 error
+		body, err2 = json.Marshal(info)
+		if err2 != nil {
+			log.Printf("ERROR!  Unable to marshal IIIFInfo response: %s", err2)
+			http.Error(w, "Server error", 500)
+			return
+		}
+
+		if respCache != nil {
+			if etag, modTime, ok := sourceETag(res.FilePath); ok {
+				respCache.Set(cacheKey, &cache.Entry{Body: body, ContentType: ct, ETag: etag, ModTime: modTime})
+			}
+		}
+	}
+
+	// Conditional requests only apply to the cached, service-free
+	// representation: once a services block is embedded, a 304 could send
+	// a client back to a different principal's cached copy.
+	if cached != nil && services == nil {
+		if notModified(w, req, cached) {
+			return
+		}
+		setCacheHeaders(w, cached)
+	}
+
+	out, err := addAuthServices(body, services)
 	if err != nil {
-		log.Printf("ERROR!  Unable to marshal IIIFInfo response: %s", err)
+		log.Printf("ERROR!  Unable to embed auth services in IIIFInfo response: %s", err)
 		http.Error(w, "Server error", 500)
 		return
 	}
 
 	// Set headers - content type is dependent on client
-	ct := "application/json"
-	if acceptsLD(req) {
-		ct = "application/ld+json"
-	}
 	w.Header().Set("Content-Type", ct)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write(json)
+	w.Write(out)
+}
+
+// readOrientation returns the EXIF orientation tag embedded near the
+// start of the file at path, or orientation.Normal if it can't be read.
+// Only the leading bytes are read since the tag always lives in the
+// header, not the pixel data.
+func readOrientation(path string) orientation.Tag {
+	f, err := os.Open(path)
+	if err != nil {
+		return orientation.Normal
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(f, orientationMaxRead))
+	if err != nil {
+		return orientation.Normal
+	}
+
+	return orientation.Read(data)
+}
+
+// readICCProfile returns the ICC color profile embedded near the start of
+// the file at path, or nil if it can't be read or none is present. Only
+// the leading bytes are read since, like the EXIF tag, it lives in the
+// header, not the pixel data.
+func readICCProfile(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(f, orientationMaxRead))
+	if err != nil {
+		return nil
+	}
+
+	return orientation.ReadICCProfile(data)
 }
 
 // Handles image processing operations.  Putting resize into the IIIFImage
@@ -153,16 +499,130 @@ func (ih *IIIFHandler) Command(w http.ResponseWriter, req *http.Request, u *iiif
 		return
 	}
 
-	img, err := res.Apply(u)
-	if err != nil {
+	format := negotiateFormat(req, u)
+	quality := requestedQuality(req)
+
+	var cacheKey cache.Key
+	if respCache != nil {
+		_, modTime, ok := sourceETag(res.FilePath)
+		cacheKey = cache.Key{Identifier: res.ID.String(), URL: req.RequestURI, Format: format, Quality: quality}
+		if ok {
+			if e, hit := respCache.Get(cacheKey); hit && e.ModTime.Equal(modTime) {
+				if notModified(w, req, e) {
+					return
+				}
+				setCacheHeaders(w, e)
+				w.Header().Set("Content-Type", e.ContentType)
+				w.Write(e.Body)
+				return
+			}
+		}
+	}
+
+	// Hand the decode/resize off to the bounded scaler pool so a burst of
+	// expensive requests can't exhaust CPU. The scheduler can only cancel
+	// work still waiting for a slot: ctx has no hook into the underlying
+	// decoder, so once decoding starts a client disconnect doesn't abort
+	// it mid-flight, it just means we skip encoding/writing a result
+	// nobody will receive.
+	var img image.Image
+	waitStart := time.Now()
+	err := scaler.Submit(req.Context(), func(ctx context.Context) error {
+		scheduler.QueueWaitSeconds.Observe(time.Since(waitStart).Seconds())
+		decodeStart := time.Now()
+		var applyErr error
+		img, applyErr = res.Apply(u)
+		if applyErr != nil {
+			scheduler.DecodeSeconds.Observe(time.Since(decodeStart).Seconds())
+			return applyErr
+		}
+
+		// NOTE: ideally orientation/ICC correction would run on the full
+		// decoded image before region/size, so a request's region/size
+		// coordinates (which info.json's swapped dimensions imply are in
+		// post-orientation space) crop against the same space they're
+		// expressed in. res.Apply(u) is the only decode entry point
+		// available here and it bundles decode+region+size+90-degree
+		// rotation into one call, so there's no hook to run orientation in
+		// between; this applies it to the already-cropped tile instead,
+		// which is only correct for unrotated-region requests (e.g.
+		// full/full/...). Fixing this for real requires orientation
+		// awareness inside ImageResource.Apply itself.
+		if !DisableOrientation {
+			if tag := readOrientation(res.FilePath); tag != orientation.Normal {
+				img = orientation.Apply(img, tag)
+			}
+		}
+		if iccData := readICCProfile(res.FilePath); len(iccData) > 0 {
+			if corrected, iccErr := orientation.ConvertToSRGB(img, iccData); iccErr == nil {
+				img = corrected
+			} else {
+				log.Printf("Unable to convert ICC profile for %s: %s", res.ID, iccErr)
+			}
+		}
+
+		// res.Apply(u) already satisfies mirroring and any rotation that's a
+		// multiple of 90 degrees (the decoder's own RotationBy90s/Mirroring
+		// support), so this stage only has to pick up the remainder: an
+		// arbitrary angle the decoder can't do. Re-running Mirror or a
+		// 90/180/270 Rotate here would double up on work the decoder already
+		// did and hand back a wrongly-transformed image.
+		if math.Mod(u.Rotation.Degrees, 90) != 0 {
+			img = rotate.Rotate(img, u.Rotation.Degrees, rotateBackground(format))
+		}
+
+		scheduler.DecodeSeconds.Observe(time.Since(decodeStart).Seconds())
+		return ctx.Err()
+	})
+
+	switch err {
+	case nil:
+		scheduler.JobsSubmitted.WithLabelValues("success").Inc()
+	case scheduler.ErrQueueFull:
+		scheduler.JobsSubmitted.WithLabelValues("queue_full").Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, "Server is too busy to process this request; try again shortly", 503)
+		return
+	case scheduler.ErrQueueTimeout:
+		scheduler.JobsSubmitted.WithLabelValues("timeout").Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, "Server is too busy to process this request; try again shortly", 503)
+		return
+	case context.Canceled, context.DeadlineExceeded:
+		scheduler.JobsSubmitted.WithLabelValues("canceled").Inc()
+		return
+	default:
+		scheduler.JobsSubmitted.WithLabelValues("error").Inc()
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
-	// Encode as JPEG straight to the client
-	if err = jpeg.Encode(w, img, &jpeg.Options{Quality: 80}); err != nil {
-		http.Error(w, "Unable to encode jpeg", 500)
-		log.Println("Unable to encode JPEG:", err)
+	// Encode using whichever codec was negotiated from the URL suffix or
+	// Accept header, honoring any per-request ?q= quality override.
+	encodeStart := time.Now()
+	var buf bytes.Buffer
+	opts := encoder.Options{Quality: quality}
+	if err = encoder.Encode(&buf, img, format, opts); err != nil {
+		http.Error(w, "Unable to encode image", 500)
+		log.Printf("Unable to encode to %s: %s", format, err)
 		return
 	}
+	scheduler.EncodeSeconds.Observe(time.Since(encodeStart).Seconds())
+	scheduler.OutputBytes.Observe(float64(buf.Len()))
+
+	ct := ""
+	if enc, ok := encoder.Lookup(format); ok {
+		ct = enc.ContentType()
+		w.Header().Set("Content-Type", ct)
+	}
+
+	if respCache != nil {
+		if etag, modTime, ok := sourceETag(res.FilePath); ok {
+			respCache.Set(cacheKey, &cache.Entry{Body: buf.Bytes(), ContentType: ct, ETag: etag, ModTime: modTime})
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "public, max-age=86400")
+		}
+	}
+
+	w.Write(buf.Bytes())
 }
\ No newline at end of file
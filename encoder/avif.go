@@ -0,0 +1,58 @@
+//go:build avif
+
+package encoder
+
+// #cgo pkg-config: libavif
+// #include <avif/avif.h>
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"unsafe"
+)
+
+type avifEncoder struct{}
+
+func (avifEncoder) Format() string      { return "avif" }
+func (avifEncoder) ContentType() string { return "image/avif" }
+
+func (avifEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	rgba := toRGBA(img)
+	q := opts.Quality
+	if q <= 0 {
+		q = 60
+	}
+
+	image := C.avifImageCreate(C.uint32_t(rgba.Rect.Dx()), C.uint32_t(rgba.Rect.Dy()), 8, C.AVIF_PIXEL_FORMAT_YUV420)
+	defer C.avifImageDestroy(image)
+
+	var rgbImg C.avifRGBImage
+	C.avifRGBImageSetDefaults(&rgbImg, image)
+	rgbImg.pixels = (*C.uint8_t)(unsafe.Pointer(&rgba.Pix[0]))
+	rgbImg.rowBytes = C.uint32_t(rgba.Stride)
+	if C.avifImageRGBToYUV(image, &rgbImg) != C.AVIF_RESULT_OK {
+		return fmt.Errorf("encoder: avifImageRGBToYUV failed")
+	}
+
+	encoder := C.avifEncoderCreate()
+	defer C.avifEncoderDestroy(encoder)
+	encoder.quality = C.int(q)
+	encoder.minQuantizer = 0
+	encoder.maxQuantizer = 63
+
+	var output C.avifRWData
+	defer C.avifRWDataFree(&output)
+	if C.avifEncoderWrite(encoder, image, &output) != C.AVIF_RESULT_OK {
+		return fmt.Errorf("encoder: avifEncoderWrite failed")
+	}
+
+	buf := C.GoBytes(unsafe.Pointer(output.data), C.int(output.size))
+	_, err := w.Write(buf)
+	return err
+}
+
+func init() {
+	Register(avifEncoder{})
+}
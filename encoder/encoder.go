@@ -0,0 +1,90 @@
+// Package encoder provides a pluggable output-encoder pipeline for
+// IIIFHandler.Command, decoupling response encoding from routing.  Each
+// supported format registers an Encoder; callers look one up by IIIF
+// format suffix (jpg, png, ...) rather than hardcoding a single codec.
+package encoder
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// Options carries the per-request knobs an Encoder may honor.  Quality
+// sets the lossy level for JPEG, WebP and AVIF, picks the compression level
+// for PNG, and is ignored by GIF and TIFF; it is clamped to the server's
+// configured policy before an Encoder ever sees it.
+type Options struct {
+	// Quality is 1-100; zero means "use the encoder's default".
+	Quality int
+}
+
+// Encoder writes an image.Image to w in a specific output format.
+type Encoder interface {
+	// Encode writes img to w using opts.
+	Encode(w io.Writer, img image.Image, opts Options) error
+
+	// Format is the IIIF format suffix this Encoder handles, e.g. "jpg".
+	Format() string
+
+	// ContentType is the MIME type sent in the response's Content-Type
+	// header for this format.
+	ContentType() string
+}
+
+var registry = map[string]Encoder{}
+
+// Register adds enc to the set of available encoders, keyed by its
+// Format().  Register is meant to be called from package init()
+// functions, mirroring how the individual format encoders wire
+// themselves up; a format compiled out (e.g. WebP without its CGo
+// dependency) simply never registers.
+func Register(enc Encoder) {
+	registry[enc.Format()] = enc
+}
+
+// Lookup returns the Encoder registered for format, and whether one was
+// found.
+func Lookup(format string) (Encoder, bool) {
+	enc, ok := registry[format]
+	return enc, ok
+}
+
+// SupportedFormats returns the IIIF format suffixes that have a
+// registered Encoder, suitable for FeatureSet to advertise only the
+// formats actually compiled into this binary.
+func SupportedFormats() []string {
+	formats := make([]string, 0, len(registry))
+	for f := range registry {
+		formats = append(formats, f)
+	}
+	return formats
+}
+
+// Encode looks up the Encoder for format and uses it to write img to w.
+// It returns an error if no Encoder is registered for format.
+func Encode(w io.Writer, img image.Image, format string, opts Options) error {
+	enc, ok := Lookup(format)
+	if !ok {
+		return fmt.Errorf("encoder: no encoder registered for format %q", format)
+	}
+	return enc.Encode(w, img, opts)
+}
+
+// toRGBA returns img as an *image.RGBA, converting it if it isn't already
+// one. The CGo encoders (WebP, AVIF) need a packed RGBA pixel buffer to
+// hand to their C APIs; this lives here, untagged, rather than in one of
+// those build-tagged files, so either can be built without the other.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+	return rgba
+}
@@ -0,0 +1,52 @@
+package encoder
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 60), B: 1, A: 255})
+		}
+	}
+	return img
+}
+
+// TestLookupAndEncode checks that every registered encoder can be looked
+// up and encodes a small image without error, writing a non-empty result.
+// The output is not decoded back.
+func TestLookupAndEncode(t *testing.T) {
+	for _, format := range SupportedFormats() {
+		t.Run(format, func(t *testing.T) {
+			enc, ok := Lookup(format)
+			if !ok {
+				t.Fatalf("Lookup(%q) reported false after SupportedFormats listed it", format)
+			}
+
+			var buf bytes.Buffer
+			if err := enc.Encode(&buf, testImage(), Options{Quality: 80}); err != nil {
+				t.Fatalf("Encode(%q) = %v", format, err)
+			}
+			if buf.Len() == 0 {
+				t.Fatalf("Encode(%q) wrote no bytes", format)
+			}
+		})
+	}
+}
+
+func TestLookupUnknownFormat(t *testing.T) {
+	if _, ok := Lookup("not-a-real-format"); ok {
+		t.Fatalf("Lookup of an unregistered format should report false")
+	}
+}
+
+func TestEncodeUnknownFormat(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, testImage(), "not-a-real-format", Options{}); err == nil {
+		t.Fatalf("Encode with an unregistered format should return an error")
+	}
+}
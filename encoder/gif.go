@@ -0,0 +1,20 @@
+package encoder
+
+import (
+	"image"
+	"image/gif"
+	"io"
+)
+
+type gifEncoder struct{}
+
+func (gifEncoder) Format() string      { return "gif" }
+func (gifEncoder) ContentType() string { return "image/gif" }
+
+func (gifEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	return gif.Encode(w, img, nil)
+}
+
+func init() {
+	Register(gifEncoder{})
+}
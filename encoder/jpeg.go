@@ -0,0 +1,30 @@
+package encoder
+
+import (
+	"image"
+	"image/jpeg"
+	"io"
+)
+
+// defaultJPEGQuality is used when a request doesn't specify ?q=.
+const defaultJPEGQuality = 80
+
+type jpegEncoder struct{}
+
+func (jpegEncoder) Format() string      { return "jpg" }
+func (jpegEncoder) ContentType() string { return "image/jpeg" }
+
+func (jpegEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	q := opts.Quality
+	if q <= 0 {
+		q = defaultJPEGQuality
+	}
+
+	// image/jpeg only ever writes baseline JPEGs; there's no progressive
+	// option to request even if we wanted one.
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: q})
+}
+
+func init() {
+	Register(jpegEncoder{})
+}
@@ -0,0 +1,30 @@
+package encoder
+
+import (
+	"image"
+	"image/png"
+	"io"
+)
+
+type pngEncoder struct{}
+
+func (pngEncoder) Format() string      { return "png" }
+func (pngEncoder) ContentType() string { return "image/png" }
+
+func (pngEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	enc := png.Encoder{CompressionLevel: png.DefaultCompression}
+
+	// Higher requested quality favors smaller files over encode speed.
+	switch {
+	case opts.Quality > 0 && opts.Quality < 40:
+		enc.CompressionLevel = png.NoCompression
+	case opts.Quality >= 90:
+		enc.CompressionLevel = png.BestCompression
+	}
+
+	return enc.Encode(w, img)
+}
+
+func init() {
+	Register(pngEncoder{})
+}
@@ -0,0 +1,20 @@
+package encoder
+
+import (
+	"golang.org/x/image/tiff"
+	"image"
+	"io"
+)
+
+type tiffEncoder struct{}
+
+func (tiffEncoder) Format() string      { return "tif" }
+func (tiffEncoder) ContentType() string { return "image/tiff" }
+
+func (tiffEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+}
+
+func init() {
+	Register(tiffEncoder{})
+}
@@ -0,0 +1,46 @@
+//go:build webp
+
+package encoder
+
+// #cgo pkg-config: libwebp
+// #include <webp/encode.h>
+// #include <stdlib.h>
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"unsafe"
+)
+
+type webpEncoder struct{}
+
+func (webpEncoder) Format() string      { return "webp" }
+func (webpEncoder) ContentType() string { return "image/webp" }
+
+func (webpEncoder) Encode(w io.Writer, img image.Image, opts Options) error {
+	rgba := toRGBA(img)
+	q := float32(opts.Quality)
+	if q <= 0 {
+		q = 75
+	}
+
+	var output *C.uint8_t
+	size := C.WebPEncodeRGBA(
+		(*C.uint8_t)(unsafe.Pointer(&rgba.Pix[0])),
+		C.int(rgba.Rect.Dx()), C.int(rgba.Rect.Dy()), C.int(rgba.Stride),
+		C.float(q), &output)
+	if size == 0 {
+		return fmt.Errorf("encoder: WebPEncodeRGBA failed")
+	}
+	defer C.WebPFree(unsafe.Pointer(output))
+
+	buf := C.GoBytes(unsafe.Pointer(output), C.int(size))
+	_, err := w.Write(buf)
+	return err
+}
+
+func init() {
+	Register(webpEncoder{})
+}
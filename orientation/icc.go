@@ -0,0 +1,65 @@
+//go:build icc
+
+package orientation
+
+// #cgo pkg-config: lcms2
+// #include <lcms2.h>
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// ConvertToSRGB parses the ICC profile embedded in an image's source
+// bytes (iccData) and converts img's pixels from that profile's color
+// space into sRGB, so colors stay consistent across browsers regardless
+// of how the source was tagged. A nil/empty iccData is a no-op.
+func ConvertToSRGB(img image.Image, iccData []byte) (image.Image, error) {
+	if len(iccData) == 0 {
+		return img, nil
+	}
+
+	srcProfile := C.cmsOpenProfileFromMem(unsafe.Pointer(&iccData[0]), C.cmsUInt32Number(len(iccData)))
+	if srcProfile == nil {
+		return nil, fmt.Errorf("orientation: unreadable ICC profile")
+	}
+	defer C.cmsCloseProfile(srcProfile)
+
+	dstProfile := C.cmsCreate_sRGBProfile()
+	defer C.cmsCloseProfile(dstProfile)
+
+	transform := C.cmsCreateTransform(
+		srcProfile, C.TYPE_RGBA_8,
+		dstProfile, C.TYPE_RGBA_8,
+		C.INTENT_PERCEPTUAL, 0)
+	if transform == nil {
+		return nil, fmt.Errorf("orientation: unable to build ICC transform")
+	}
+	defer C.cmsDeleteTransform(transform)
+
+	b := img.Bounds()
+	rgba := toRGBA(img)
+	out := image.NewRGBA(b)
+	copy(out.Pix, rgba.Pix)
+
+	pixelCount := C.cmsUInt32Number(b.Dx() * b.Dy())
+	C.cmsDoTransform(transform, unsafe.Pointer(&out.Pix[0]), unsafe.Pointer(&out.Pix[0]), pixelCount)
+
+	return out, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+	return rgba
+}
@@ -0,0 +1,12 @@
+//go:build !icc
+
+package orientation
+
+import "image"
+
+// ConvertToSRGB is a no-op when this binary isn't built with the icc tag
+// (i.e. without the lcms2 CGo dependency available). Images are passed
+// through unchanged rather than failing the request.
+func ConvertToSRGB(img image.Image, iccData []byte) (image.Image, error) {
+	return img, nil
+}
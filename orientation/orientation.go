@@ -0,0 +1,218 @@
+// Package orientation reads EXIF orientation tags and embedded ICC color
+// profiles from source images, and applies the corresponding rotation,
+// flip, and color-space correction so decoded pixels and reported
+// dimensions match what a viewer expects rather than what the raw file
+// bytes happen to encode.
+//
+// Orientation determines the *effective* width/height reported in
+// info.json (see Tag.Dimensions). IIIFHandler.Command runs Apply on the
+// output of ImageResource.Apply, that is after IIIF region/size processing,
+// not before it.
+package orientation
+
+import (
+	"bytes"
+	"image"
+	"sort"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// Tag is the raw EXIF orientation value (1-8), per the TIFF/EXIF spec.
+// Tag 1 (or a missing tag) means no correction is needed.
+type Tag int
+
+const (
+	Normal Tag = iota + 1
+	_           // 2: flip horizontal - not produced by common cameras, handled generically below
+	Rotate180
+	FlipVertical
+	Transpose
+	Rotate90
+	Transverse
+	Rotate270
+)
+
+// Read returns the EXIF orientation tag embedded in data, or Normal if
+// none is present or the data has no readable EXIF block.
+func Read(data []byte) Tag {
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Normal
+	}
+
+	tag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return Normal
+	}
+
+	v, err := tag.Int(0)
+	if err != nil {
+		return Normal
+	}
+
+	return Tag(v)
+}
+
+// iccAPP2Marker is the leading identifier of a JPEG APP2 segment carrying
+// (a chunk of) an embedded ICC profile, per the ICC spec's "Embedding ICC
+// Profiles in JFIF Files" recommendation.
+const iccAPP2Marker = "ICC_PROFILE\x00"
+
+// ReadICCProfile extracts an embedded ICC color profile from a JPEG's raw
+// bytes, reassembling it from one or more APP2 "ICC_PROFILE" marker
+// segments in their declared chunk order. It returns nil if data isn't a
+// JPEG, or carries no profile.
+func ReadICCProfile(data []byte) []byte {
+	type chunk struct {
+		seq, total byte
+		bytes      []byte
+	}
+	var chunks []chunk
+
+	for i := 0; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			i++
+			continue
+		}
+		m := data[i+1]
+		switch {
+		case m == 0xD8 || m == 0x01 || (m >= 0xD0 && m <= 0xD7):
+			// Markers with no payload.
+			i += 2
+			continue
+		case m == 0xD9 || m == 0xDA:
+			// End-of-image or start-of-scan: no more metadata segments follow.
+			i = len(data)
+			continue
+		}
+
+		if i+4 > len(data) {
+			break
+		}
+		segLen := int(data[i+2])<<8 | int(data[i+3])
+		if segLen < 2 || i+2+segLen > len(data) {
+			break
+		}
+		seg := data[i+4 : i+2+segLen]
+
+		if m == 0xE2 && len(seg) > len(iccAPP2Marker)+2 && string(seg[:len(iccAPP2Marker)]) == iccAPP2Marker {
+			rest := seg[len(iccAPP2Marker):]
+			chunks = append(chunks, chunk{seq: rest[0], total: rest[1], bytes: rest[2:]})
+		}
+
+		i += 2 + segLen
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	sort.Slice(chunks, func(a, b int) bool { return chunks[a].seq < chunks[b].seq })
+	var profile []byte
+	for _, c := range chunks {
+		profile = append(profile, c.bytes...)
+	}
+	return profile
+}
+
+// Dimensions swaps w/h to reflect the effective size after Tag's rotation
+// is applied, so callers building info.json report post-orientation
+// dimensions rather than the raw decoded ones.
+func (t Tag) Dimensions(w, h int) (effW, effH int) {
+	switch t {
+	case Transpose, Rotate90, Transverse, Rotate270:
+		return h, w
+	default:
+		return w, h
+	}
+}
+
+// Apply rotates/flips img according to t, per the standard EXIF
+// orientation semantics, returning img unchanged for Normal (or any
+// unrecognized tag).
+func Apply(img image.Image, t Tag) image.Image {
+	switch t {
+	case Rotate180:
+		return rotate180(img)
+	case 2: // flip horizontal
+		return flip(img, true, false)
+	case FlipVertical:
+		return flip(img, false, true)
+	case Transpose:
+		return transpose(img, false)
+	case Rotate270:
+		return rotate90(img, true)
+	case Transverse:
+		return transpose(img, true)
+	case Rotate90:
+		return rotate90(img, false)
+	default:
+		return img
+	}
+}
+
+func rotate180(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(b.Max.X-1-(x-b.Min.X), b.Max.Y-1-(y-b.Min.Y), img.At(x, y))
+		}
+	}
+	return dst
+}
+
+func flip(img image.Image, horizontal, vertical bool) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dx, dy := x, y
+			if horizontal {
+				dx = b.Max.X - 1 - (x - b.Min.X)
+			}
+			if vertical {
+				dy = b.Max.Y - 1 - (y - b.Min.Y)
+			}
+			dst.Set(dx, dy, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+// rotate90 rotates img 90 degrees clockwise, or counter-clockwise when ccw
+// is true.
+func rotate90(img image.Image, ccw bool) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sx, sy := x-b.Min.X, y-b.Min.Y
+			if ccw {
+				dst.Set(sy, b.Dx()-1-sx, img.At(x, y))
+			} else {
+				dst.Set(b.Dy()-1-sy, sx, img.At(x, y))
+			}
+		}
+	}
+	return dst
+}
+
+// transpose mirrors img across its main diagonal, or its anti-diagonal
+// when anti is true (EXIF's Transverse orientation).
+func transpose(img image.Image, anti bool) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sx, sy := x-b.Min.X, y-b.Min.Y
+			if anti {
+				dst.Set(b.Dy()-1-sy, b.Dx()-1-sx, img.At(x, y))
+			} else {
+				dst.Set(sy, sx, img.At(x, y))
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,115 @@
+package orientation
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkerboard builds a small, asymmetric RGBA image so rotation/flip bugs
+// (e.g. swapped axes) show up as pixel mismatches rather than passing by
+// coincidence on a symmetric test image.
+func checkerboard(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 1, A: 255})
+		}
+	}
+	return img
+}
+
+func at(img image.Image, x, y int) color.RGBA {
+	r, g, b, a := img.At(x, y).RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+}
+
+// TestApplyRoundTrip checks that applying a tag's correction and then the
+// inverse operation recovers the original pixels, which is the property
+// EXIF consumers rely on: Tag N paired with Dimensions(w, h) should leave
+// the image indistinguishable from one a normally-oriented camera would
+// have produced.
+func TestApplyRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		tag     Tag
+		inverse Tag
+	}{
+		{"Rotate180", Rotate180, Rotate180},
+		{"FlipVertical", FlipVertical, FlipVertical},
+		{"Transpose", Transpose, Transpose},
+		{"Transverse", Transverse, Transverse},
+		{"Rotate90/Rotate270", Rotate90, Rotate270},
+		{"Rotate270/Rotate90", Rotate270, Rotate90},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src := checkerboard(5, 3)
+			got := Apply(Apply(src, c.tag), c.inverse)
+
+			b := src.Bounds()
+			if got.Bounds().Dx() != b.Dx() || got.Bounds().Dy() != b.Dy() {
+				t.Fatalf("round trip changed dimensions: got %v, want %v", got.Bounds(), b)
+			}
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if want, have := at(src, x, y), at(got, x, y); want != have {
+						t.Fatalf("pixel (%d,%d): want %+v, got %+v", x, y, want, have)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestTagDimensionsMatchesRotate90 pins down the EXIF-to-rotation mapping
+// that was previously inverted: tag 6 (Rotate90) must rotate 90 degrees
+// clockwise, and tag 8 (Rotate270) 270 degrees clockwise (90 CCW). A
+// clockwise 90-degree rotation moves the source's top-left pixel to the
+// destination's top-right corner.
+func TestTagDimensionsMatchesRotate90(t *testing.T) {
+	src := checkerboard(5, 3)
+
+	rotated := Apply(src, Rotate90)
+	w, h := rotated.Bounds().Dx(), rotated.Bounds().Dy()
+	if w != src.Bounds().Dy() || h != src.Bounds().Dx() {
+		t.Fatalf("Rotate90 dimensions: got %dx%d, want %dx%d", w, h, src.Bounds().Dy(), src.Bounds().Dx())
+	}
+	if want, have := at(src, 0, 0), at(rotated, w-1, 0); want != have {
+		t.Fatalf("Rotate90 (tag 6) should be clockwise: top-left source pixel should land top-right, want %+v got %+v", want, have)
+	}
+
+	effW, effH := Rotate90.Dimensions(src.Bounds().Dx(), src.Bounds().Dy())
+	if effW != w || effH != h {
+		t.Fatalf("Dimensions(%d,%d) for Rotate90 = (%d,%d), want (%d,%d)", src.Bounds().Dx(), src.Bounds().Dy(), effW, effH, w, h)
+	}
+}
+
+// TestReadICCProfileRoundTrip builds a minimal JPEG-like byte stream with
+// a single-chunk APP2 ICC_PROFILE segment and checks it's extracted
+// verbatim.
+func TestReadICCProfileRoundTrip(t *testing.T) {
+	profile := []byte("fake icc profile data")
+
+	var data []byte
+	data = append(data, 0xFF, 0xD8) // SOI
+	seg := append([]byte("ICC_PROFILE\x00"), 1, 1)
+	seg = append(seg, profile...)
+	segLen := len(seg) + 2
+	data = append(data, 0xFF, 0xE2, byte(segLen>>8), byte(segLen))
+	data = append(data, seg...)
+	data = append(data, 0xFF, 0xDA) // SOS
+
+	got := ReadICCProfile(data)
+	if string(got) != string(profile) {
+		t.Fatalf("ReadICCProfile = %q, want %q", got, profile)
+	}
+}
+
+func TestReadICCProfileAbsent(t *testing.T) {
+	data := []byte{0xFF, 0xD8, 0xFF, 0xDA}
+	if got := ReadICCProfile(data); got != nil {
+		t.Fatalf("ReadICCProfile on a profile-less JPEG = %q, want nil", got)
+	}
+}
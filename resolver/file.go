@@ -0,0 +1,38 @@
+package resolver
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+// FileResolver resolves identifiers against a local directory tree. This
+// is RAIS's original behavior, preserved as the default when no scheme is
+// given.
+type FileResolver struct {
+	Root string
+}
+
+// NewFileResolver returns a FileResolver rooted at root.
+func NewFileResolver(root string) *FileResolver {
+	return &FileResolver{Root: root}
+}
+
+func (fr *FileResolver) Resolve(id iiif.ID) (io.ReaderAt, int64, time.Time, error) {
+	path := fr.Root + "/" + id.Path()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, time.Time{}, err
+	}
+
+	return f, fi.Size(), fi.ModTime(), nil
+}
@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+// GCSResolver resolves identifiers against objects in a Google Cloud
+// Storage bucket, fetching byte ranges on demand so decoders don't need
+// the whole object downloaded up front.
+type GCSResolver struct {
+	Bucket string
+	Prefix string
+	client *storage.Client
+}
+
+// NewGCSResolver returns a GCSResolver for the given bucket, prefixing
+// identifiers with prefix to form the object name.
+func NewGCSResolver(bucket, prefix string) *GCSResolver {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		// Deferred to first Resolve call, which will surface a clear error
+		// rather than panicking during server startup.
+		client = nil
+	}
+	return &GCSResolver{Bucket: bucket, Prefix: prefix, client: client}
+}
+
+func (gr *GCSResolver) object(id iiif.ID) string {
+	if gr.Prefix == "" {
+		return id.Path()
+	}
+	return gr.Prefix + "/" + id.Path()
+}
+
+func (gr *GCSResolver) Resolve(id iiif.ID) (io.ReaderAt, int64, time.Time, error) {
+	if gr.client == nil {
+		return nil, 0, time.Time{}, fmt.Errorf("resolver: GCS client failed to initialize at startup; check credentials for bucket %q", gr.Bucket)
+	}
+
+	obj := gr.client.Bucket(gr.Bucket).Object(gr.object(id))
+
+	attrs, err := obj.Attrs(context.Background())
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+
+	return &gcsRangeReader{obj: obj}, attrs.Size, attrs.Updated, nil
+}
+
+// gcsRangeReader implements io.ReaderAt over a GCS object using ranged
+// reads. In practice RAIS's openjpeg decoder needs a real file
+// descriptor, so resolvePath spools the whole object to a temp file
+// before decoding; this still pays off for any consumer that only needs
+// a byte range (e.g. a future pure-Go/streaming decoder).
+type gcsRangeReader struct {
+	obj *storage.ObjectHandle
+}
+
+func (r *gcsRangeReader) ReadAt(p []byte, off int64) (int, error) {
+	rc, err := r.obj.NewRangeReader(context.Background(), off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	return readFull(rc, p)
+}
@@ -0,0 +1,20 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+// TestGCSResolverResolveNilClient checks that a GCSResolver whose client
+// failed to initialize at construction time (NewGCSResolver leaves
+// client nil rather than panicking) returns a descriptive error from
+// Resolve instead of nil-panicking on gr.client.Bucket(...).
+func TestGCSResolverResolveNilClient(t *testing.T) {
+	gr := &GCSResolver{Bucket: "my-bucket"}
+
+	_, _, _, err := gr.Resolve(iiif.ID("foo.jp2"))
+	if err == nil {
+		t.Fatalf("Resolve() with a nil client should return an error, not panic or succeed")
+	}
+}
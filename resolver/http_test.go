@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+func TestHTTPResolverResolveUsesContentLength(t *testing.T) {
+	body := []byte("hello world")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			w.Header().Set("Content-Length", "11")
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	hr := NewHTTPResolver(srv.URL)
+	r, size, _, err := hr.Resolve(iiif.ID("foo.jp2"))
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if size != int64(len(body)) {
+		t.Fatalf("size = %d, want %d", size, len(body))
+	}
+
+	p := make([]byte, len(body))
+	n, err := r.ReadAt(p, 0)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if n != len(body) || string(p) != string(body) {
+		t.Fatalf("ReadAt = %q, want %q", p[:n], body)
+	}
+}
+
+// TestHTTPResolverResolveProbesMissingContentLength checks the fallback
+// path for origins that omit Content-Length on HEAD (size reports -1):
+// Resolve must probe the real size via a ranged GET rather than handing
+// callers a bogus -1, which would break SpoolToTemp.
+func TestHTTPResolverResolveProbesMissingContentLength(t *testing.T) {
+	body := []byte("hello world")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			// No Content-Length set: Go's http.Client reports -1 for this.
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-0/11")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body[:1])
+	}))
+	defer srv.Close()
+
+	hr := NewHTTPResolver(srv.URL)
+	_, size, _, err := hr.Resolve(iiif.ID("foo.jp2"))
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if size != int64(len(body)) {
+		t.Fatalf("size = %d, want %d (probed via Content-Range)", size, len(body))
+	}
+}
+
+func TestHTTPResolverResolveHeadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	hr := NewHTTPResolver(srv.URL)
+	if _, _, _, err := hr.Resolve(iiif.ID("missing.jp2")); err == nil {
+		t.Fatalf("Resolve of a 404 should return an error")
+	}
+}
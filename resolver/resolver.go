@@ -0,0 +1,67 @@
+// Package resolver abstracts away where source image bytes come from, so
+// IIIFHandler doesn't have to assume every identifier maps to a path on a
+// mounted filesystem. The active Resolver is chosen by the URL scheme of
+// the configured tile path ("file://", "s3://bucket/prefix",
+// "https://origin/", ...), letting RAIS front a cloud bucket of JP2s
+// without requiring a mounted filesystem.
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/uoregon-libraries/rais-image-server/iiif"
+)
+
+// Resolver resolves a IIIF identifier to its source bytes, along with the
+// size and modification time needed for cache validation and
+// Content-Length headers.
+type Resolver interface {
+	// Resolve returns a ReaderAt over id's source bytes, its size, and its
+	// last-modified time. Decoders that need a real file descriptor (e.g.
+	// openjpeg) should spool r to a temp file rather than assuming this is
+	// backed by the local filesystem.
+	Resolve(id iiif.ID) (r io.ReaderAt, size int64, mtime time.Time, err error)
+}
+
+// readFull fills p from r, the way io.ReadFull does, except a short read
+// at the end of the underlying range reports io.EOF rather than
+// io.ErrUnexpectedEOF. io.ReadFull's ErrUnexpectedEOF is meant for
+// streaming reads that expect an exact-length blob; callers satisfying
+// io.ReaderAt's contract, like the range readers in this package, hit
+// short reads routinely (the last page of an object) and must surface a
+// plain EOF so callers such as io.SectionReader behave correctly.
+func readFull(r io.Reader, p []byte) (int, error) {
+	n, err := io.ReadFull(r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// New builds the Resolver appropriate for base, dispatching on its URL
+// scheme. A bare path with no scheme is treated the same as "file://".
+func New(base string) (Resolver, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		path := base
+		if u != nil && u.Scheme == "file" {
+			path = u.Path
+		}
+		return NewFileResolver(path), nil
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTPResolver(base), nil
+	case "s3":
+		return NewS3Resolver(u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	case "gs":
+		return NewGCSResolver(u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	default:
+		return nil, fmt.Errorf("resolver: unsupported scheme %q in tile path %q", u.Scheme, base)
+	}
+}
@@ -0,0 +1,35 @@
+package resolver
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestReadFullShortReadIsEOF pins io.ReaderAt's contract: a short read at
+// the end of the underlying range must surface as io.EOF, not
+// io.ErrUnexpectedEOF, which is what the underlying io.ReadFull would
+// otherwise report and which io.SectionReader and friends don't expect
+// from a well-behaved ReaderAt.
+func TestReadFullShortReadIsEOF(t *testing.T) {
+	r := bytes.NewReader([]byte("abc"))
+	p := make([]byte, 10)
+
+	n, err := readFull(r, p)
+	if n != 3 {
+		t.Fatalf("readFull() n = %d, want 3", n)
+	}
+	if err != io.EOF {
+		t.Fatalf("readFull() err = %v, want io.EOF", err)
+	}
+}
+
+func TestReadFullExactRead(t *testing.T) {
+	r := bytes.NewReader([]byte("abc"))
+	p := make([]byte, 3)
+
+	n, err := readFull(r, p)
+	if n != 3 || err != nil {
+		t.Fatalf("readFull() = %d, %v, want 3, nil", n, err)
+	}
+}
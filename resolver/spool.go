@@ -0,0 +1,28 @@
+package resolver
+
+import (
+	"io"
+	"os"
+)
+
+// SpoolToTemp copies size bytes from r into a new temp file and returns
+// its path, for decoders (like openjpeg) that need a real file descriptor
+// rather than an io.ReaderAt. The caller must invoke the returned cleanup
+// func to remove the temp file once decoding is done.
+func SpoolToTemp(r io.ReaderAt, size int64) (path string, cleanup func(), err error) {
+	f, err := os.CreateTemp("", "rais-spool-*")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, err := io.Copy(f, io.NewSectionReader(r, 0, size)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", nil, err
+	}
+
+	name := f.Name()
+	f.Close()
+
+	return name, func() { os.Remove(name) }, nil
+}
@@ -0,0 +1,80 @@
+package rotate
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func checkerboard(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 1, A: 255})
+		}
+	}
+	return img
+}
+
+func at(img image.Image, x, y int) color.RGBA {
+	r, g, b, a := img.At(x, y).RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+}
+
+func TestMirror(t *testing.T) {
+	src := checkerboard(4, 3)
+	got := Mirror(src)
+
+	if got.Bounds().Dx() != src.Bounds().Dx() || got.Bounds().Dy() != src.Bounds().Dy() {
+		t.Fatalf("Mirror changed dimensions: got %v, want %v", got.Bounds(), src.Bounds())
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			if want, have := at(src, x, y), at(got, 3-x, y); want != have {
+				t.Fatalf("pixel (%d,%d): want %+v, got %+v", x, y, want, have)
+			}
+		}
+	}
+}
+
+// TestRotate90Dimensions checks the expanded-canvas math for a clean
+// 90-degree turn, where the bounding box is exactly the transposed
+// original (no padding should be needed).
+func TestRotate90Dimensions(t *testing.T) {
+	src := checkerboard(6, 4)
+	dst := Rotate(src, 90, color.White)
+
+	wantW, wantH := src.Bounds().Dy(), src.Bounds().Dx()
+	if dst.Bounds().Dx() != wantW || dst.Bounds().Dy() != wantH {
+		t.Fatalf("Rotate(90) dimensions = %v, want %dx%d", dst.Bounds(), wantW, wantH)
+	}
+}
+
+// TestRotateZeroIsNoop ensures a zero-degree rotation is returned
+// unchanged rather than re-rendered (which would needlessly lose the
+// original image's concrete type and introduce resampling artifacts).
+func TestRotateZeroIsNoop(t *testing.T) {
+	src := checkerboard(4, 3)
+	got := Rotate(src, 0, color.White)
+	if got != image.Image(src) {
+		t.Fatalf("Rotate(0) should return img unchanged")
+	}
+}
+
+// TestRotateBackgroundFillsCorners checks that a 45-degree rotation, which
+// necessarily exposes background in the expanded canvas's corners, fills
+// them with the requested bg color rather than leaving them transparent
+// black.
+func TestRotateBackgroundFillsCorners(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	dst := Rotate(src, 45, color.White)
+	if have := at(dst, 0, 0); have.R != 255 || have.G != 255 || have.B != 255 {
+		t.Fatalf("corner of a 45-degree rotation should be filled with the bg color, got %+v", have)
+	}
+}
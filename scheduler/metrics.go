@@ -0,0 +1,53 @@
+package scheduler
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics groups the Prometheus collectors exported by a Scheduler so
+// operators can see resize-pipeline saturation: how many jobs succeed,
+// get rejected for a full queue, or time out waiting for a slot, plus how
+// long each stage takes.
+var (
+	JobsSubmitted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rais_scaler_jobs_total",
+			Help: "Count of resize jobs submitted to the scaler pool, by outcome (success, queue_full, timeout, canceled, error).",
+		},
+		[]string{"outcome"},
+	)
+
+	QueueWaitSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "rais_scaler_queue_wait_seconds",
+			Help:    "Time a resize job spent waiting for a free worker slot.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	DecodeSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "rais_scaler_decode_seconds",
+			Help:    "Time spent decoding the source image for a resize job.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	EncodeSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "rais_scaler_encode_seconds",
+			Help:    "Time spent encoding the resized output for a resize job.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	OutputBytes = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "rais_scaler_output_bytes",
+			Help:    "Size in bytes of the encoded output produced by a resize job.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 10),
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(JobsSubmitted, QueueWaitSeconds, DecodeSeconds, EncodeSeconds, OutputBytes)
+}
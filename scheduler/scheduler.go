@@ -0,0 +1,81 @@
+// Package scheduler provides a bounded worker pool for serializing
+// expensive, CGo-backed decode/resize work behind a semaphore.  Callers
+// submit jobs through Submit, which blocks until a slot is free, the
+// context is cancelled, or the configured wait timeout elapses.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrQueueTimeout is returned by Submit when a job waits longer than the
+// scheduler's configured timeout for a free slot.
+var ErrQueueTimeout = errors.New("scheduler: timed out waiting for a free worker slot")
+
+// ErrQueueFull is returned by Submit when the queue is already at its
+// configured limit and cannot accept more waiters.
+var ErrQueueFull = errors.New("scheduler: queue is full")
+
+// Job is the unit of work a Scheduler runs.  Implementations should honor
+// context cancellation so a client disconnect can abort in-flight decode
+// work.
+type Job func(ctx context.Context) error
+
+// Scheduler serializes Jobs behind a semaphore of a configurable size, so
+// a burst of expensive resize requests can't exhaust available CPU.
+type Scheduler struct {
+	sem         chan struct{}
+	waiting     chan struct{}
+	maxQueue    int
+	waitTimeout time.Duration
+}
+
+// New returns a Scheduler allowing up to maxProcs jobs to run
+// concurrently.  maxQueue bounds how many additional jobs may wait for a
+// slot; once that many are already waiting, Submit returns ErrQueueFull
+// immediately.  waitTimeout bounds how long a job may wait for a slot
+// before Submit returns ErrQueueTimeout.
+func New(maxProcs, maxQueue int, waitTimeout time.Duration) *Scheduler {
+	return &Scheduler{
+		sem:         make(chan struct{}, maxProcs),
+		waiting:     make(chan struct{}, maxQueue),
+		maxQueue:    maxQueue,
+		waitTimeout: waitTimeout,
+	}
+}
+
+// Submit runs job once a worker slot is available, blocking the caller
+// until then.  It returns ErrQueueFull if too many jobs are already
+// waiting, ErrQueueTimeout if the wait exceeds the scheduler's
+// waitTimeout, or ctx.Err() if ctx is cancelled first.  Otherwise it
+// returns whatever job itself returns.
+func (s *Scheduler) Submit(ctx context.Context, job Job) error {
+	select {
+	case s.waiting <- struct{}{}:
+	default:
+		return ErrQueueFull
+	}
+
+	timer := time.NewTimer(s.waitTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.sem <- struct{}{}:
+		// Release the queue slot as soon as we stop waiting on it, so
+		// maxQueue bounds only jobs still waiting for a worker, not
+		// waiting+running combined; otherwise maxQueue == maxProcs would
+		// leave no room for anything to ever queue.
+		<-s.waiting
+	case <-timer.C:
+		<-s.waiting
+		return ErrQueueTimeout
+	case <-ctx.Done():
+		<-s.waiting
+		return ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	return job(ctx)
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubmitSuccess(t *testing.T) {
+	s := New(1, 1, time.Second)
+	err := s.Submit(context.Background(), func(ctx context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("Submit() = %v, want nil", err)
+	}
+}
+
+func TestSubmitPropagatesJobError(t *testing.T) {
+	s := New(1, 1, time.Second)
+	want := errors.New("boom")
+	err := s.Submit(context.Background(), func(ctx context.Context) error { return want })
+	if err != want {
+		t.Fatalf("Submit() = %v, want %v", err, want)
+	}
+}
+
+// TestSubmitQueueFull checks that a waiter beyond maxQueue gets
+// ErrQueueFull immediately, distinct from ErrQueueTimeout. maxQueue
+// bounds jobs still waiting for a worker slot, not running ones, so the
+// first Submit below occupies the only worker slot (sem) and runs, while
+// the second actually parks waiting for that slot to free up (it can't
+// signal this directly — its job body doesn't run until it's past the
+// wait — so the test gives it a moment to reach the blocking select,
+// same as TestSubmitQueueTimeout/TestSubmitContextCanceledWhileWaiting
+// below). Only then does a third Submit find the queue full.
+func TestSubmitQueueFull(t *testing.T) {
+	s := New(1, 1, time.Second)
+
+	occupied := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Submit(context.Background(), func(ctx context.Context) error {
+			close(occupied)
+			<-release
+			return nil
+		})
+	}()
+	<-occupied
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Submit(context.Background(), func(ctx context.Context) error { return nil })
+	}()
+	time.Sleep(20 * time.Millisecond) // let the goroutine above take the queue's only waiting slot
+
+	if err := s.Submit(context.Background(), func(ctx context.Context) error { return nil }); err != ErrQueueFull {
+		t.Fatalf("Submit() on a full queue = %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestSubmitQueueTimeout(t *testing.T) {
+	s := New(1, 2, 10*time.Millisecond)
+
+	release := make(chan struct{})
+	go s.Submit(context.Background(), func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond) // let the goroutine above take the only worker slot
+
+	err := s.Submit(context.Background(), func(ctx context.Context) error { return nil })
+	if err != ErrQueueTimeout {
+		t.Fatalf("Submit() waiting past waitTimeout = %v, want ErrQueueTimeout", err)
+	}
+	close(release)
+}
+
+func TestSubmitContextCanceledWhileWaiting(t *testing.T) {
+	s := New(1, 2, time.Second)
+
+	release := make(chan struct{})
+	go s.Submit(context.Background(), func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.Submit(ctx, func(ctx context.Context) error { return nil })
+	if err != context.Canceled {
+		t.Fatalf("Submit() with a canceled ctx = %v, want context.Canceled", err)
+	}
+	close(release)
+}